@@ -0,0 +1,263 @@
+// Package analysis computes a per-second colour/intensity strip ("moodbar")
+// for a track, so the TUI can draw a coloured progress bar under the seek
+// line instead of a flat one.
+package analysis
+
+import (
+	"crypto/sha1"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// Result is a strip of colours in playback order. The beep-based decoder
+// and the solid-colour fallback give one colour per second of the track;
+// output of the external moodbar binary is resampled to a strip of about
+// 180 colours whatever the track's length.
+type Result []color.RGBA
+
+// Source is everything Analyze needs to describe a track. FilePath is
+// empty when only a remote streaming URL is available, in which case
+// Analyze falls back to a solid colour derived from PicURL.
+type Source struct {
+	SongID   string
+	FilePath string
+	PicURL   string
+	Duration time.Duration
+}
+
+// Analyzer computes and disk-caches moodbar strips, keyed by song ID.
+type Analyzer struct {
+	cacheDir   string
+	moodbarBin string // external `moodbar` binary; empty disables it in favor of the beep-based fallback
+}
+
+// NewAnalyzer builds an Analyzer that caches results under cacheDir and,
+// when moodbarBin is non-empty and on PATH, shells out to it instead of
+// decoding audio itself.
+func NewAnalyzer(cacheDir, moodbarBin string) *Analyzer {
+	_ = os.MkdirAll(cacheDir, 0755)
+	if moodbarBin != "" {
+		if _, err := exec.LookPath(moodbarBin); err != nil {
+			moodbarBin = ""
+		}
+	}
+	return &Analyzer{cacheDir: cacheDir, moodbarBin: moodbarBin}
+}
+
+// Analyze returns the moodbar strip for src, computing and caching it if
+// this is the first time this song ID has been seen.
+func (a *Analyzer) Analyze(src Source) (Result, error) {
+	if result, ok := a.loadCache(src.SongID); ok {
+		return result, nil
+	}
+
+	result, err := a.compute(src)
+	if err != nil {
+		return fallbackResult(src), err
+	}
+
+	a.saveCache(src.SongID, result)
+	return result, nil
+}
+
+func (a *Analyzer) compute(src Source) (Result, error) {
+	switch {
+	case src.FilePath == "":
+		// Streaming URL: the raw audio isn't reachable locally.
+		return fallbackResult(src), nil
+	case a.moodbarBin != "":
+		return runMoodbarBinary(a.moodbarBin, src.FilePath)
+	default:
+		return decodeAndAnalyze(src.FilePath)
+	}
+}
+
+// runMoodbarBinary shells out to the external `moodbar` tool, which
+// writes a `.mood` file next to its input: a flat array of uint8 RGB
+// triples, one per roughly 0.1% of the track. resampleMoodFile thins that
+// to about 180 colours; the track's duration is not used.
+func runMoodbarBinary(bin, filePath string) (Result, error) {
+	moodFile := filePath + ".mood"
+	cmd := exec.Command(bin, filePath, "-o", moodFile)
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("moodbar: %w", err)
+	}
+	defer os.Remove(moodFile)
+
+	data, err := os.ReadFile(moodFile)
+	if err != nil {
+		return nil, err
+	}
+	return resampleMoodFile(data), nil
+}
+
+// resampleMoodFile keeps every step-th triple of a raw moodbar RGB-triple
+// stream, with step = triples/180: a file of at least 180 triples yields
+// between 180 and 359 colours, a shorter one yields one colour per triple.
+// The samples are not tied to seconds of the track.
+func resampleMoodFile(data []byte) Result {
+	triples := len(data) / 3
+	if triples == 0 {
+		return nil
+	}
+
+	const secondsTarget = 180 // a reasonable strip length regardless of track length; the TUI stretches it to fit
+	step := triples / secondsTarget
+	if step < 1 {
+		step = 1
+	}
+
+	var result Result
+	for i := 0; i < triples; i += step {
+		off := i * 3
+		result = append(result, color.RGBA{R: data[off], G: data[off+1], B: data[off+2], A: 0xff})
+	}
+	return result
+}
+
+// decodeAndAnalyze is the fallback when no `moodbar` binary is
+// configured: it walks the raw PCM samples a second at a time, buckets
+// them into three crude frequency bands via a direct DFT (bass/mid/
+// treble), and maps relative band energy to hue/saturation/value.
+func decodeAndAnalyze(filePath string) (Result, error) {
+	samples, sampleRate, err := decodePCM(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if sampleRate == 0 {
+		return nil, fmt.Errorf("analysis: unknown sample rate for %s", filePath)
+	}
+
+	window := sampleRate
+	var result Result
+	for start := 0; start < len(samples); start += window {
+		end := start + window
+		if end > len(samples) {
+			end = len(samples)
+		}
+		result = append(result, bandEnergyToColor(samples[start:end], sampleRate))
+	}
+	return result, nil
+}
+
+// bandEnergyToColor runs a tiny three-bin DFT over a one-second window and
+// maps relative bass/mid/treble energy onto hue/saturation/value.
+func bandEnergyToColor(window []float64, sampleRate int) color.RGBA {
+	bands := []float64{80, 1000, 6000} // bass, mid, treble reference frequencies in Hz
+	energy := make([]float64, len(bands))
+
+	for i, freq := range bands {
+		var real, imag float64
+		w := 2 * math.Pi * freq / float64(sampleRate)
+		for n, s := range window {
+			real += s * math.Cos(w*float64(n))
+			imag -= s * math.Sin(w*float64(n))
+		}
+		energy[i] = math.Hypot(real, imag) / float64(len(window))
+	}
+
+	total := energy[0] + energy[1] + energy[2]
+	if total == 0 {
+		return color.RGBA{A: 0xff}
+	}
+
+	// Bass -> red (0deg), mid -> green (120deg), treble -> blue (240deg),
+	// weighted by each band's share of total energy. Weighting only by
+	// treble's share collapses bass-only and treble-only tracks onto the
+	// same red hue and makes blue unreachable; weighting all three bands
+	// against their fixed hues avoids both.
+	hue := (energy[1]*120 + energy[2]*240) / total
+	sat := math.Min(1, total*4)
+	val := math.Min(1, total*8)
+	return hsvToRGBA(hue, sat, val)
+}
+
+// fallbackResult is used for streaming URLs (no local file to decode) and
+// on any analysis failure: a single solid colour, repeated across the
+// whole strip, derived deterministically from the track's album art URL.
+func fallbackResult(src Source) Result {
+	c := colorFromString(src.PicURL)
+	seconds := int(src.Duration.Seconds())
+	if seconds <= 0 {
+		seconds = 1
+	}
+	result := make(Result, seconds)
+	for i := range result {
+		result[i] = c
+	}
+	return result
+}
+
+// colorFromString derives a deterministic, pleasant-ish colour from a
+// string (typically an album art URL) without fetching or decoding the
+// image itself.
+func colorFromString(s string) color.RGBA {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	hue := float64(h.Sum32()%360)
+	return hsvToRGBA(hue, 0.55, 0.85)
+}
+
+func hsvToRGBA(h, s, v float64) color.RGBA {
+	c := v * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := v - c
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	return color.RGBA{
+		R: uint8((r + m) * 0xff),
+		G: uint8((g + m) * 0xff),
+		B: uint8((b + m) * 0xff),
+		A: 0xff,
+	}
+}
+
+func (a *Analyzer) cachePath(songID string) string {
+	sum := sha1.Sum([]byte(songID))
+	return filepath.Join(a.cacheDir, fmt.Sprintf("%x.mood.gob", sum))
+}
+
+func (a *Analyzer) loadCache(songID string) (Result, bool) {
+	f, err := os.Open(a.cachePath(songID))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	var result Result
+	if err := gob.NewDecoder(f).Decode(&result); err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+func (a *Analyzer) saveCache(songID string, result Result) {
+	f, err := os.Create(a.cachePath(songID))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_ = gob.NewEncoder(f).Encode(result)
+}
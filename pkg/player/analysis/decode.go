@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/wav"
+)
+
+// decodePCM decodes filePath into a single channel of float64 samples in
+// [-1, 1], downmixing stereo by averaging. Only wav/mp3 are supported
+// here; anything else falls through to the caller's fallback path.
+func decodePCM(filePath string) ([]float64, int, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	var (
+		stream beep.StreamSeekCloser
+		format beep.Format
+	)
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".mp3":
+		stream, format, err = mp3.Decode(f)
+	default:
+		stream, format, err = wav.Decode(f)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer stream.Close()
+
+	buf := make([][2]float64, 512)
+	samples := make([]float64, 0, stream.Len())
+	for {
+		n, ok := stream.Stream(buf)
+		for i := 0; i < n; i++ {
+			samples = append(samples, (buf[i][0]+buf[i][1])/2)
+		}
+		if !ok {
+			break
+		}
+	}
+
+	return samples, int(format.SampleRate), nil
+}
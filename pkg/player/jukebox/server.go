@@ -0,0 +1,205 @@
+// Package jukebox exposes a go-musicfox player.Player as a Subsonic
+// jukeboxControl HTTP endpoint, so Subsonic clients (DSub, Symfonium, ...)
+// can drive a headless instance the way they would a Navidrome jukebox
+// device.
+package jukebox
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-musicfox/pkg/player"
+)
+
+// QueuedPlayer is the subset of player.Player a backend needs to expose to
+// back the jukeboxControl verbs: the shared Queue, basic transport
+// control, and Play to actually load whatever the queue now points at
+// (Resume only un-pauses whatever MPD already has loaded, which is never
+// the new track after a skip/set/start).
+type QueuedPlayer interface {
+	Queue() *player.Queue
+	Play(songType player.SongType, url string, duration time.Duration)
+	Toggle()
+	Stop()
+	Resume()
+	State() player.State
+}
+
+// SongResolver turns a Subsonic song id into a playable UrlMusic, backed
+// by whatever catalog layer owns song lookup.
+type SongResolver func(id string) (player.UrlMusic, bool)
+
+// Server implements the Subsonic jukeboxControl.view endpoint.
+type Server struct {
+	p       QueuedPlayer
+	resolve SongResolver
+}
+
+// NewServer wraps p as a Subsonic jukeboxControl HTTP handler, resolving
+// the `id` query params that `add`/`set` receive via resolve. Mount it at
+// `/rest/jukeboxControl` (or `.view`) alongside the rest of a Subsonic API
+// shim.
+func NewServer(p QueuedPlayer, resolve SongResolver) *Server {
+	return &Server{p: p, resolve: resolve}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("action") {
+	case "get":
+		s.get(w, r)
+	case "status":
+		s.status(w, r)
+	case "set":
+		s.set(w, r)
+	case "start":
+		s.playCurrent(w, r)
+	case "stop":
+		s.p.Stop()
+		s.status(w, r)
+	case "skip":
+		s.skip(w, r)
+	case "add":
+		s.add(w, r)
+	case "clear":
+		s.p.Queue().Clear()
+		s.status(w, r)
+	case "remove":
+		s.remove(w, r)
+	case "shuffle":
+		s.p.Queue().SetShuffle(true)
+		s.status(w, r)
+	case "setGain":
+		s.setGain(w, r)
+	default:
+		writeError(w, 70, "unknown jukeboxControl action")
+	}
+}
+
+func (s *Server) get(w http.ResponseWriter, _ *http.Request) {
+	tracks, current, _, _, _ := s.p.Queue().Snapshot()
+	entries := make([]jukeboxEntry, len(tracks))
+	for i, t := range tracks {
+		entries[i] = jukeboxEntry{Title: t.Title, Artist: t.Artist, Album: t.Album}
+	}
+	writeXML(w, jukeboxPlaylist{CurrentIndex: current, Entries: entries})
+}
+
+func (s *Server) status(w http.ResponseWriter, _ *http.Request) {
+	_, current, shuffle, _, gain := s.p.Queue().Snapshot()
+	playing := s.p.State() == player.Playing
+	writeXML(w, jukeboxStatus{CurrentIndex: current, Playing: playing, Gain: gain, Shuffle: shuffle})
+}
+
+func (s *Server) set(w http.ResponseWriter, r *http.Request) {
+	s.p.Queue().Clear()
+	s.add(w, r)
+}
+
+// add resolves every repeated `id` query param to a playable UrlMusic and
+// appends them to the queue. Ids that fail to resolve are skipped rather
+// than failing the whole request, since a client may be batching several
+// songs at once.
+func (s *Server) add(w http.ResponseWriter, r *http.Request) {
+	ids := r.URL.Query()["id"]
+	tracks := make([]player.UrlMusic, 0, len(ids))
+	for _, id := range ids {
+		if music, ok := s.resolve(id); ok {
+			tracks = append(tracks, music)
+		}
+	}
+	s.p.Queue().Add(tracks...)
+	s.status(w, r)
+}
+
+func (s *Server) skip(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		writeError(w, 10, "missing or invalid index")
+		return
+	}
+	music, ok := s.p.Queue().Skip(index)
+	if !ok {
+		writeError(w, 70, "index out of range")
+		return
+	}
+	s.p.Play(music.Type, music.Url, music.Duration)
+	s.status(w, r)
+}
+
+// playCurrent backs the "start" verb: it loads whatever the queue
+// currently points at into the backend, rather than just Resume()-ing
+// whatever MPD already happened to have loaded.
+func (s *Server) playCurrent(w http.ResponseWriter, r *http.Request) {
+	music, ok := s.p.Queue().Current()
+	if !ok {
+		writeError(w, 70, "queue is empty")
+		return
+	}
+	s.p.Play(music.Type, music.Url, music.Duration)
+	s.status(w, r)
+}
+
+func (s *Server) remove(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		writeError(w, 10, "missing or invalid index")
+		return
+	}
+	s.p.Queue().Remove(index)
+	s.status(w, r)
+}
+
+func (s *Server) setGain(w http.ResponseWriter, r *http.Request) {
+	gain, err := strconv.ParseFloat(r.URL.Query().Get("gain"), 64)
+	if err != nil {
+		writeError(w, 10, "missing or invalid gain")
+		return
+	}
+	s.p.Queue().SetGain(gain)
+	s.status(w, r)
+}
+
+type jukeboxStatus struct {
+	XMLName      xml.Name `xml:"jukeboxStatus"`
+	CurrentIndex int      `xml:"currentIndex,attr"`
+	Playing      bool     `xml:"playing,attr"`
+	Gain         float64  `xml:"gain,attr"`
+	Shuffle      bool     `xml:"shuffle,attr"`
+}
+
+type jukeboxPlaylist struct {
+	XMLName      xml.Name       `xml:"jukeboxPlaylist"`
+	CurrentIndex int            `xml:"currentIndex,attr"`
+	Entries      []jukeboxEntry `xml:"entry"`
+}
+
+type jukeboxEntry struct {
+	Title  string `xml:"title,attr"`
+	Artist string `xml:"artist,attr"`
+	Album  string `xml:"album,attr"`
+}
+
+type subsonicError struct {
+	XMLName xml.Name `xml:"subsonic-response"`
+	Status  string   `xml:"status,attr"`
+	Error   struct {
+		Code    int    `xml:"code,attr"`
+		Message string `xml:"message,attr"`
+	} `xml:"error"`
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	resp := subsonicError{Status: "failed"}
+	resp.Error.Code = code
+	resp.Error.Message = message
+	w.WriteHeader(http.StatusOK)
+	writeXML(w, resp)
+}
@@ -1,13 +1,20 @@
 package player
 
 import (
+	"context"
 	"fmt"
-	"go-musicfox/utils"
+	"hash/fnv"
+	"image/color"
 	"os/exec"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go-musicfox/pkg/player/analysis"
+	"go-musicfox/utils"
+
 	"github.com/fhs/gompd/v2/mpd"
 )
 
@@ -28,27 +35,81 @@ func mpdErrorHandler(err error, ignore bool) {
 	}
 }
 
+// reconnectBackoff is the schedule used while rebuilding the MPD client and
+// watcher subscription after a connection drop.
+var reconnectBackoff = []time.Duration{
+	200 * time.Millisecond, 500 * time.Millisecond, time.Second,
+	2 * time.Second, 5 * time.Second,
+}
+
+// cmdKind identifies a request to the mpdPlayer actor goroutine.
+type cmdKind int
+
+const (
+	playCmd cmdKind = iota
+	pauseCmd
+	resumeCmd
+	stopCmd
+	seekCmd
+	upVolumeCmd
+	downVolumeCmd
+	syncCmd
+	watchEventCmd
+	reconnectCmd
+)
+
+// actorCmd is a single request handed to the actor goroutine that owns all
+// of mpdPlayer's mutable state. reply, when non-nil, receives exactly one
+// value once the command has been applied.
+type actorCmd struct {
+	kind      cmdKind
+	music     UrlMusic
+	seekTo    time.Duration
+	subsystem string
+	reply     chan error
+}
+
+// mpdPlayer drives MPD over a single actor goroutine (run()): every public
+// method enqueues an actorCmd and waits for it to be applied instead of
+// guarding fields with a mutex. This is what lets Play() block until the
+// command is actually accepted (rather than silently dropping it on a full
+// channel) and makes Close() deterministic.
 type mpdPlayer struct {
 	bin        string
 	configFile string
 	network    string
 	address    string
 
-	watcher *mpd.Watcher
-	l       sync.Mutex
+	watcherVal atomic.Value // *watcherGen, swapped wholesale by doReconnect
 
 	curMusic       UrlMusic
 	curSongId      int
-	timer          *utils.Timer
 	latestPlayTime time.Time //避免切歌时产生的stop信号造成影响
 
-	volume    int
-	state     State
-	timeChan  chan time.Duration
-	stateChan chan State
-	musicChan chan UrlMusic
+	queue *Queue
 
-	close chan struct{}
+	analyzer *analysis.Analyzer // nil unless SetAnalyzer was called; gates the moodbar pipeline
+	moodChan chan []color.RGBA
+
+	volume int
+	state  State
+
+	// *Val fields mirror actor-owned state for lock-free reads from other
+	// goroutines (CurMusic/State/PassedTime are called often, by the TUI's
+	// render loop, and shouldn't round-trip through the actor).
+	curMusicVal atomic.Value // UrlMusic
+	stateVal    atomic.Value // State
+	timerVal    atomic.Value // *utils.Timer
+
+	timeChan     chan time.Duration
+	stateChan    chan State
+	switcherChan chan State // private fan-out of setState, consumed only by trackSwitcher
+	errChan      chan error // non-fatal watcher/reconnect errors, surfaced to callers
+
+	cmd       chan actorCmd
+	close     chan struct{}
+	closed    chan struct{} // closed once the actor goroutine has torn everything down
+	closeOnce sync.Once
 }
 
 func NewMpdPlayer(bin, configFile, network, address string) Player {
@@ -74,20 +135,25 @@ func NewMpdPlayer(bin, configFile, network, address string) Player {
 	mpdErrorHandler(err, false)
 
 	p := &mpdPlayer{
-		bin:        bin,
-		configFile: configFile,
-		network:    network,
-		address:    address,
-		watcher:    watcher,
-		timeChan:   make(chan time.Duration),
-		stateChan:  make(chan State),
-		musicChan:  make(chan UrlMusic),
-		close:      make(chan struct{}),
+		bin:          bin,
+		configFile:   configFile,
+		network:      network,
+		address:      address,
+		queue:        NewQueue(),
+		timeChan:     make(chan time.Duration),
+		stateChan:    make(chan State),
+		switcherChan: make(chan State, 1),
+		errChan:      make(chan error, 8),
+		moodChan:     make(chan []color.RGBA, 1),
+		cmd:          make(chan actorCmd),
+		close:        make(chan struct{}),
+		closed:       make(chan struct{}),
 	}
+	p.watcherVal.Store(&watcherGen{w: watcher, next: make(chan struct{})})
 
 	go func() {
 		defer utils.Recover(false)
-		p.listen()
+		p.run()
 	}()
 
 	go func() {
@@ -95,113 +161,571 @@ func NewMpdPlayer(bin, configFile, network, address string) Player {
 		p.watch()
 	}()
 
+	go func() {
+		defer utils.Recover(false)
+		p.watchErrors()
+	}()
+
+	go func() {
+		defer utils.Recover(false)
+		p.trackSwitcher()
+	}()
+
 	p.SyncMpdStatus()
 	return p
 }
 
 var _client *mpd.Client
 
-func (p *mpdPlayer) client() *mpd.Client {
+// client returns the shared MPD command connection, redialing if it has
+// dropped. Only ever called from the actor goroutine (run()/doXxx), so it
+// needs no locking despite being backed by a package-level variable. A
+// dial failure is returned to the caller instead of panicking: every doXxx
+// already propagates its error back through send(), and client() is on
+// that exact path, so panicking here would take down run() the moment MPD
+// is briefly unreachable while any command (not just the watcher) is in
+// flight.
+func (p *mpdPlayer) client() (*mpd.Client, error) {
 	var err error
 	if _client != nil {
 		if err = _client.Ping(); err == nil {
-			return _client
+			return _client, nil
 		}
 	}
 	_client, err = mpd.Dial(p.network, p.address)
-	mpdErrorHandler(err, false)
-	return _client
+	if err != nil {
+		_client = nil
+		return nil, err
+	}
+	return _client, nil
 }
 
-func (p *mpdPlayer) SyncMpdStatus() {
-	status, err := p.client().Status()
+// watcherGen pairs a live *mpd.Watcher with a channel that's closed the
+// moment doReconnect installs its replacement. watch()/watchErrors() wait
+// on next instead of busy-spinning on the old watcher's now-closed
+// Event/Error channels while a reconnect (which can take many seconds,
+// per reconnectBackoff) is in progress.
+type watcherGen struct {
+	w    *mpd.Watcher
+	next chan struct{}
+}
+
+func (p *mpdPlayer) watcherGeneration() *watcherGen {
+	return p.watcherVal.Load().(*watcherGen)
+}
+
+func (p *mpdPlayer) timer() *utils.Timer {
+	if v := p.timerVal.Load(); v != nil {
+		return v.(*utils.Timer)
+	}
+	return nil
+}
+
+// send dispatches cmd to the actor goroutine and blocks for it to be
+// applied. Unlike the old musicChan-based Play(), this never silently
+// drops the request; it only gives up if the actor is gone or genuinely
+// wedged.
+func (p *mpdPlayer) send(cmd actorCmd) error {
+	reply := make(chan error, 1)
+	cmd.reply = reply
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	select {
+	case p.cmd <- cmd:
+	case <-p.close:
+		return fmt.Errorf("mpd player已关闭")
+	case <-ctx.Done():
+		return fmt.Errorf("mpd actor繁忙,指令超时: %w", ctx.Err())
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("mpd actor繁忙,指令超时: %w", ctx.Err())
+	}
+}
+
+// run is the single actor goroutine that owns every piece of mutable
+// mpdPlayer state. Commands are applied one at a time, in the order
+// received, which is what makes the rest of this file race-free without
+// a mutex.
+func (p *mpdPlayer) run() {
+	defer close(p.closed)
+	for {
+		select {
+		case <-p.close:
+			p.teardown()
+			return
+		case cmd := <-p.cmd:
+			err := p.handle(cmd)
+			if cmd.reply != nil {
+				cmd.reply <- err
+			}
+		}
+	}
+}
+
+func (p *mpdPlayer) handle(cmd actorCmd) error {
+	switch cmd.kind {
+	case playCmd:
+		return p.doPlay(cmd.music)
+	case pauseCmd:
+		return p.doPause()
+	case resumeCmd:
+		return p.doResume()
+	case stopCmd:
+		return p.doStop()
+	case seekCmd:
+		return p.doSeek(cmd.seekTo)
+	case upVolumeCmd:
+		return p.doUpVolume()
+	case downVolumeCmd:
+		return p.doDownVolume()
+	case syncCmd:
+		return p.doSync()
+	case watchEventCmd:
+		return p.doWatchEvent(cmd.subsystem)
+	case reconnectCmd:
+		return p.doReconnect()
+	default:
+		return nil
+	}
+}
+
+// teardown runs on the actor goroutine once Close() signals p.close, so it
+// never races doPlay/doPause/etc.
+func (p *mpdPlayer) teardown() {
+	if t := p.timer(); t != nil {
+		t.Stop()
+	}
+
+	err := p.watcherGeneration().w.Close()
 	mpdErrorHandler(err, true)
 
+	if client, err := p.client(); err == nil {
+		mpdErrorHandler(client.Stop(), true)
+		mpdErrorHandler(client.Close(), true)
+	} else {
+		mpdErrorHandler(err, true)
+	}
+
+	cmd := exec.Command(p.bin)
+	if p.configFile != "" {
+		cmd.Args = append(cmd.Args, p.configFile)
+	}
+	cmd.Args = append(cmd.Args, "--kill")
+	_ = cmd.Run()
+
+	// Belt-and-braces: make sure no registered backend child (such as an
+	// mpv backend's process) is left running after Close() either.
+	KillAllChildren()
+}
+
+// doPlay ports the old listen() body: it owns curMusic/curSongId/timer
+// directly since it only ever runs on the actor goroutine.
+func (p *mpdPlayer) doPlay(music UrlMusic) error {
+	p.curMusic = music
+	p.curMusicVal.Store(music)
+	p.latestPlayTime = time.Now()
+
+	if err := p.doPause(); err != nil {
+		return err
+	}
+
+	if t := p.timer(); t != nil {
+		t.Stop()
+	}
+
+	client, err := p.client()
+	if err != nil {
+		return err
+	}
+
+	if p.curSongId != 0 {
+		if err := client.DeleteID(p.curSongId); err != nil {
+			mpdErrorHandler(err, true)
+		}
+	}
+
+	p.curSongId, err = client.AddID(music.Url, 0)
+	if err != nil {
+		return err
+	}
+
+	var newTimer *utils.Timer
+	newTimer = utils.NewTimer(utils.Options{
+		Duration:       8760 * time.Hour,
+		TickerInternal: 200 * time.Millisecond,
+		OnRun:          func(started bool) {},
+		OnPaused:       func() {},
+		OnDone:         func(stopped bool) {},
+		OnTick: func() {
+			select {
+			case p.timeChan <- newTimer.Passed():
+			default:
+			}
+		},
+	})
+	p.timerVal.Store(newTimer)
+
+	if err = client.PlayID(p.curSongId); err != nil {
+		return err
+	}
+
+	if p.analyzer != nil {
+		go p.analyze(music)
+	}
+
+	return p.doResume()
+}
+
+// songID derives a moodbar cache key for music that's stable across plays.
+// music.Url is frequently a signed/expiring streaming link, so two plays
+// of the same song would otherwise never share a cache entry; Title+
+// Artist+Album is what actually identifies the song. Play() only fills in
+// Url/Type/Duration, so when all three tags are empty the URL is used
+// instead, which keeps different tracks from sharing one cache entry.
+func songID(music UrlMusic) string {
+	key := music.Title + "\x00" + music.Artist + "\x00" + music.Album
+	if music.Title == "" && music.Artist == "" && music.Album == "" {
+		key = music.Url
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// analyze computes (or loads from cache) the moodbar strip for music and
+// publishes it on moodChan. Runs off the actor goroutine since it can
+// shell out to an external binary or decode the whole file.
+func (p *mpdPlayer) analyze(music UrlMusic) {
+	defer utils.Recover(false)
+
+	src := analysis.Source{
+		SongID:   songID(music),
+		PicURL:   music.PicUrl,
+		Duration: music.Duration,
+	}
+	if strings.HasPrefix(music.Url, "/") || strings.HasPrefix(music.Url, "file://") {
+		src.FilePath = strings.TrimPrefix(music.Url, "file://")
+	}
+
+	result, err := p.analyzer.Analyze(src)
+	if err != nil {
+		utils.Logger().Printf("moodbar analyze err: %+v", err)
+	}
+
+	// A quick track change can leave this goroutine still computing (the
+	// external moodbar binary or a full beep decode both take real time)
+	// after a later analyze() for the new track has already published its
+	// result, so moodChan would end up carrying the wrong track's strip
+	// with nothing downstream able to tell. Drop it if music is no longer
+	// what's actually playing.
+	if p.CurMusic().Url != music.Url {
+		return
+	}
+
+	select {
+	case p.moodChan <- result:
+	default:
+	}
+}
+
+func (p *mpdPlayer) doPause() error {
+	if p.state != Playing {
+		return nil
+	}
+	client, err := p.client()
+	if err != nil {
+		return err
+	}
+	if err := client.Pause(true); err != nil {
+		return err
+	}
+	if t := p.timer(); t != nil {
+		t.Pause()
+	}
+	p.setState(Paused)
+	return nil
+}
+
+func (p *mpdPlayer) doResume() error {
+	if p.state == Playing {
+		return nil
+	}
+	client, err := p.client()
+	if err != nil {
+		return err
+	}
+	if err := client.Pause(false); err != nil {
+		return err
+	}
+	if t := p.timer(); t != nil {
+		go t.Run()
+	}
+	p.setState(Playing)
+	return nil
+}
+
+func (p *mpdPlayer) doStop() error {
+	if p.state == Stopped {
+		return nil
+	}
+	client, err := p.client()
+	if err != nil {
+		return err
+	}
+	if err := client.Pause(true); err != nil {
+		return err
+	}
+	if t := p.timer(); t != nil {
+		t.Pause()
+	}
+	// A user-initiated Stop is not a track ending on its own, so unlike
+	// setState it is not fanned out to trackSwitcher.
+	p.state = Stopped
+	p.stateVal.Store(Stopped)
+	select {
+	case p.stateChan <- Stopped:
+	default:
+	}
+	return nil
+}
+
+func (p *mpdPlayer) doSeek(duration time.Duration) error {
+	client, err := p.client()
+	if err != nil {
+		return err
+	}
+	if err := client.SeekCur(duration, false); err != nil {
+		return err
+	}
+	if t := p.timer(); t != nil {
+		t.SetPassed(duration)
+	}
+	return nil
+}
+
+func (p *mpdPlayer) doUpVolume() error {
+	client, err := p.client()
+	if err != nil {
+		return err
+	}
+	if p.volume+5 >= 100 {
+		p.volume = 100
+	} else {
+		p.volume += 5
+	}
+	return client.SetVolume(p.volume)
+}
+
+func (p *mpdPlayer) doDownVolume() error {
+	client, err := p.client()
+	if err != nil {
+		return err
+	}
+	if p.volume-5 <= 0 {
+		p.volume = 0
+	} else {
+		p.volume -= 5
+	}
+	return client.SetVolume(p.volume)
+}
+
+func (p *mpdPlayer) doSync() error {
+	client, err := p.client()
+	if err != nil {
+		return err
+	}
+	status, err := client.Status()
+	if err != nil {
+		return err
+	}
+
 	p.volume, _ = strconv.Atoi(status["volume"])
 	p.setState(stateMapping[status["state"]])
 	duration, _ := time.ParseDuration(status["elapsed"] + "s")
 
-	if p.timer != nil {
-		p.timer.SetPassed(duration)
+	if t := p.timer(); t != nil {
+		t.SetPassed(duration)
 		select {
-		case p.timeChan <- p.timer.Passed():
+		case p.timeChan <- t.Passed():
 		default:
 		}
 	}
+	return nil
 }
 
-// listen 开始监听
-func (p *mpdPlayer) listen() {
-	var (
-		err error
-	)
+// doWatchEvent applies an MPD idle event relayed by watch(). Routing it
+// through the actor (instead of watch() calling SyncMpdStatus directly)
+// keeps latestPlayTime a plain field instead of another atomic.
+func (p *mpdPlayer) doWatchEvent(subsystem string) error {
+	if subsystem == "mixer" {
+		return p.doSync()
+	}
+	//避免切歌时产生的stop信号造成影响
+	if subsystem == "player" && time.Since(p.latestPlayTime) >= time.Second*2 {
+		return p.doSync()
+	}
+	return nil
+}
 
+// doReconnect rebuilds the command client and watcher subscription after a
+// dropped connection, retrying with backoff. curSongId/volume/state are
+// ordinary actor-owned fields, so they survive the gap untouched; doSync
+// reconciles them against MPD once the new connection is up.
+//
+// The old generation's `next` channel is only closed once a replacement
+// watcher is actually installed (or the player is shutting down), which is
+// what lets watch()/watchErrors() block instead of busy-spinning on the
+// old watcher's now-closed Event/Error channels for the whole backoff
+// window.
+func (p *mpdPlayer) doReconnect() error {
+	_client = nil // force client() to redial
+
+	old := p.watcherGeneration()
+	_ = old.w.Close()
+
+	for attempt := 0; ; attempt++ {
+		watcher, err := mpd.NewWatcher(p.network, p.address, "", "player", "mixer")
+		if err == nil {
+			p.watcherVal.Store(&watcherGen{w: watcher, next: make(chan struct{})})
+			close(old.next)
+			break
+		}
+
+		utils.Logger().Printf("mpd reconnect attempt %d failed: %+v", attempt+1, err)
+		wait := reconnectBackoff[len(reconnectBackoff)-1]
+		if attempt < len(reconnectBackoff) {
+			wait = reconnectBackoff[attempt]
+		}
+		select {
+		case <-p.close:
+			close(old.next) // don't leave watch()/watchErrors() blocked forever on shutdown
+			return nil
+		case <-time.After(wait):
+		}
+	}
+
+	return p.doSync()
+}
+
+func (p *mpdPlayer) setState(state State) {
+	p.state = state
+	p.stateVal.Store(state)
+	select {
+	case p.stateChan <- state:
+	default:
+	}
+	select {
+	case p.switcherChan <- state:
+	default:
+	}
+}
+
+// watch relays MPD idle events to the actor goroutine. It must not touch
+// any actor-owned field itself (that's the whole point of the refactor),
+// so an event is just forwarded as a watchEventCmd.
+func (p *mpdPlayer) watch() {
 	for {
+		gen := p.watcherGeneration()
 		select {
 		case <-p.close:
 			return
-		case p.curMusic = <-p.musicChan:
-			p.latestPlayTime = time.Now()
-			p.Paused()
-			// 重置
-			{
-				if p.timer != nil {
-					p.timer.Stop()
-				}
-				if p.curSongId != 0 {
-					err = p.client().DeleteID(p.curSongId)
-					mpdErrorHandler(err, true)
+		case subSystem, ok := <-gen.w.Event:
+			if !ok {
+				// The watcher was closed out from under us by a
+				// reconnect; block on gen.next instead of spinning until
+				// doReconnect actually installs (and signals) the
+				// replacement, which can take the whole backoff window.
+				select {
+				case <-p.close:
+					return
+				case <-gen.next:
 				}
+				continue
+			}
+			select {
+			case p.cmd <- actorCmd{kind: watchEventCmd, subsystem: subSystem}:
+			case <-p.close:
+				return
 			}
-
-			p.curSongId, err = p.client().AddID(p.curMusic.Url, 0)
-			mpdErrorHandler(err, false)
-
-			// 计时器
-			p.timer = utils.NewTimer(utils.Options{
-				Duration:       8760 * time.Hour,
-				TickerInternal: 200 * time.Millisecond,
-				OnRun:          func(started bool) {},
-				OnPaused:       func() {},
-				OnDone:         func(stopped bool) {},
-				OnTick: func() {
-					select {
-					case p.timeChan <- p.timer.Passed():
-					default:
-					}
-				},
-			})
-
-			err = p.client().PlayID(p.curSongId)
-			mpdErrorHandler(err, false)
-			p.Resume()
 		}
 	}
 }
 
-func (p *mpdPlayer) watch() {
+// watchErrors drains the watcher's Error channel for the player's
+// lifetime. A transient MPD disconnect surfaces here instead of
+// panicking: the error is forwarded on errChan for callers to observe,
+// and a reconnect is triggered on the actor goroutine.
+func (p *mpdPlayer) watchErrors() {
 	for {
+		gen := p.watcherGeneration()
 		select {
 		case <-p.close:
 			return
-		case subSystem := <-p.watcher.Event:
-			if subSystem == "mixer" {
-				p.SyncMpdStatus()
+		case err, ok := <-gen.w.Error:
+			if !ok {
+				// Same reasoning as watch(): wait for the replacement
+				// generation instead of busy-looping on a closed channel.
+				select {
+				case <-p.close:
+					return
+				case <-gen.next:
+				}
+				continue
+			}
+			if err == nil {
+				continue
+			}
+			utils.Logger().Printf("mpd watcher err: %+v", err)
+			select {
+			case p.errChan <- err:
+			default:
+			}
+			select {
+			case p.cmd <- actorCmd{kind: reconnectCmd}:
+			case <-p.close:
 				return
 			}
-			//避免切歌时产生的stop信号造成影响
-			if subSystem == "player" && time.Now().Sub(p.latestPlayTime) >= time.Second*2 {
-				p.SyncMpdStatus()
+		}
+	}
+}
+
+// Queue returns the jukebox-style playback queue backing this player. A
+// Subsonic jukeboxControl HTTP server mutates it directly; trackSwitcher
+// advances it whenever the current track runs out.
+func (p *mpdPlayer) Queue() *Queue {
+	return p.queue
+}
+
+// trackSwitcher watches for the player going idle (the current track ended
+// on its own, as opposed to a user-initiated Stop) and advances the queue,
+// mirroring Navidrome's jukebox device model.
+func (p *mpdPlayer) trackSwitcher() {
+	for {
+		select {
+		case <-p.close:
+			return
+		case state := <-p.switcherChan:
+			if state != Stopped {
+				continue
 			}
+			music, ok := p.queue.Next()
+			if !ok {
+				continue
+			}
+			p.Play(music.Type, music.Url, music.Duration)
 		}
 	}
 }
 
-func (p *mpdPlayer) setState(state State) {
-	p.state = state
-	select {
-	case p.stateChan <- state:
-	default:
+func (p *mpdPlayer) SyncMpdStatus() {
+	if err := p.send(actorCmd{kind: syncCmd}); err != nil {
+		utils.Logger().Printf("err: %+v", err)
 	}
 }
 
@@ -211,50 +735,34 @@ func (p *mpdPlayer) Play(songType SongType, url string, duration time.Duration)
 		Type:     songType,
 		Duration: duration,
 	}
-	select {
-	case p.musicChan <- music:
-	default:
+	if err := p.send(actorCmd{kind: playCmd, music: music}); err != nil {
+		utils.Logger().Printf("err: %+v", err)
 	}
 }
 
 func (p *mpdPlayer) CurMusic() UrlMusic {
-	return p.curMusic
+	if v := p.curMusicVal.Load(); v != nil {
+		return v.(UrlMusic)
+	}
+	return UrlMusic{}
 }
 
 func (p *mpdPlayer) Paused() {
-	p.l.Lock()
-	defer p.l.Unlock()
-	if p.state != Playing {
-		return
+	if err := p.send(actorCmd{kind: pauseCmd}); err != nil {
+		utils.Logger().Printf("err: %+v", err)
 	}
-	err := p.client().Pause(true)
-	mpdErrorHandler(err, false)
-	p.timer.Pause()
-	p.setState(Paused)
 }
 
 func (p *mpdPlayer) Resume() {
-	p.l.Lock()
-	defer p.l.Unlock()
-	if p.state == Playing {
-		return
+	if err := p.send(actorCmd{kind: resumeCmd}); err != nil {
+		utils.Logger().Printf("err: %+v", err)
 	}
-	err := p.client().Pause(false)
-	mpdErrorHandler(err, false)
-	go p.timer.Run()
-	p.setState(Playing)
 }
 
 func (p *mpdPlayer) Stop() {
-	p.l.Lock()
-	defer p.l.Unlock()
-	if p.state == Stopped {
-		return
+	if err := p.send(actorCmd{kind: stopCmd}); err != nil {
+		utils.Logger().Printf("err: %+v", err)
 	}
-	err := p.client().Pause(true)
-	mpdErrorHandler(err, false)
-	p.timer.Pause()
-	p.setState(Stopped)
 }
 
 func (p *mpdPlayer) Toggle() {
@@ -267,18 +775,16 @@ func (p *mpdPlayer) Toggle() {
 }
 
 func (p *mpdPlayer) Seek(duration time.Duration) {
-	p.l.Lock()
-	defer p.l.Unlock()
-	err := p.client().SeekCur(duration, false)
-	mpdErrorHandler(err, false)
-	p.timer.SetPassed(duration)
+	if err := p.send(actorCmd{kind: seekCmd, seekTo: duration}); err != nil {
+		utils.Logger().Printf("err: %+v", err)
+	}
 }
 
 func (p *mpdPlayer) PassedTime() time.Duration {
-	if p.timer == nil {
-		return 0
+	if t := p.timer(); t != nil {
+		return t.Passed()
 	}
-	return p.timer.Passed()
+	return 0
 }
 
 func (p *mpdPlayer) TimeChan() <-chan time.Duration {
@@ -286,54 +792,55 @@ func (p *mpdPlayer) TimeChan() <-chan time.Duration {
 }
 
 func (p *mpdPlayer) State() State {
-	return p.state
+	if v := p.stateVal.Load(); v != nil {
+		return v.(State)
+	}
+	return Stopped
 }
 
 func (p *mpdPlayer) StateChan() <-chan State {
 	return p.stateChan
 }
 
+// ErrChan surfaces non-fatal errors observed on the MPD watcher connection
+// (e.g. a transient disconnect while reconnecting).
+func (p *mpdPlayer) ErrChan() <-chan error {
+	return p.errChan
+}
+
+// SetAnalyzer enables the moodbar/waveform pipeline for every track played
+// from this point on. Left nil (the default) unless config turns the
+// feature on, in which case MoodChan never receives anything.
+func (p *mpdPlayer) SetAnalyzer(a *analysis.Analyzer) {
+	p.analyzer = a
+}
+
+// MoodChan streams a freshly computed (or cached) moodbar strip each time
+// a new track starts, for the TUI to draw under the seek line.
+func (p *mpdPlayer) MoodChan() <-chan []color.RGBA {
+	return p.moodChan
+}
+
 func (p *mpdPlayer) UpVolume() {
-	p.l.Lock()
-	defer p.l.Unlock()
-	if p.volume+5 >= 100 {
-		p.volume = 100
-	} else {
-		p.volume += 5
+	if err := p.send(actorCmd{kind: upVolumeCmd}); err != nil {
+		utils.Logger().Printf("err: %+v", err)
 	}
-	_ = p.client().SetVolume(p.volume)
 }
 
 func (p *mpdPlayer) DownVolume() {
-	p.l.Lock()
-	defer p.l.Unlock()
-	if p.volume-5 <= 0 {
-		p.volume = 0
-	} else {
-		p.volume -= 5
+	if err := p.send(actorCmd{kind: downVolumeCmd}); err != nil {
+		utils.Logger().Printf("err: %+v", err)
 	}
-	_ = p.client().SetVolume(p.volume)
 }
 
+// Close is deterministic: closing p.close can never block, and every
+// actor-side cleanup happens in teardown() before p.closed is closed, so
+// this always returns once the actor goroutine has actually finished
+// (unlike the old `p.close <- struct{}{}`, which deadlocked if either
+// goroutine had already exited).
 func (p *mpdPlayer) Close() {
-	if p.timer != nil {
-		p.timer.Stop()
-	}
-	p.close <- struct{}{}
-
-	err := p.watcher.Close()
-	mpdErrorHandler(err, true)
-
-	err = p.client().Stop()
-	mpdErrorHandler(err, true)
-
-	err = p.client().Close()
-	mpdErrorHandler(err, true)
-
-	cmd := exec.Command(p.bin)
-	if p.configFile != "" {
-		cmd.Args = append(cmd.Args, p.configFile)
-	}
-	cmd.Args = append(cmd.Args, "--kill")
-	_ = cmd.Run()
+	p.closeOnce.Do(func() {
+		close(p.close)
+	})
+	<-p.closed
 }
@@ -0,0 +1,185 @@
+//go:build linux
+
+package mpris
+
+import (
+	"math"
+	"time"
+
+	"go-musicfox/pkg/player"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// rootAdapter implements org.mpris.MediaPlayer2.
+type rootAdapter struct{ h *Handler }
+
+func (rootAdapter) Raise() *dbus.Error { return nil }
+
+func (a rootAdapter) Quit() *dbus.Error {
+	a.h.p.Close()
+	return nil
+}
+
+// playerAdapter implements org.mpris.MediaPlayer2.Player.
+type playerAdapter struct{ h *Handler }
+
+func (a playerAdapter) Next() *dbus.Error {
+	a.h.ctrl.NextSong()
+	return nil
+}
+
+func (a playerAdapter) Previous() *dbus.Error {
+	a.h.ctrl.PreviousSong()
+	return nil
+}
+
+func (a playerAdapter) Pause() *dbus.Error {
+	a.h.p.Paused()
+	return nil
+}
+
+func (a playerAdapter) PlayPause() *dbus.Error {
+	a.h.p.Toggle()
+	return nil
+}
+
+func (a playerAdapter) Stop() *dbus.Error {
+	a.h.p.Stop()
+	return nil
+}
+
+func (a playerAdapter) Play() *dbus.Error {
+	a.h.p.Resume()
+	return nil
+}
+
+func (a playerAdapter) Seek(offsetUs int64) *dbus.Error {
+	a.h.p.Seek(a.h.p.PassedTime() + microsToDuration(offsetUs))
+	return nil
+}
+
+func (a playerAdapter) SetPosition(trackId dbus.ObjectPath, posUs int64) *dbus.Error {
+	a.h.p.Seek(microsToDuration(posUs))
+	return nil
+}
+
+// propSpec describes the MPRIS2 properties exported under playerIface and
+// rootIface, backed by the current Player state.
+func (h *Handler) propSpec() prop.Map {
+	return prop.Map{
+		rootIface: {
+			"CanQuit":             {Value: true, Writable: false, Emit: prop.EmitFalse},
+			"CanRaise":            {Value: false, Writable: false, Emit: prop.EmitFalse},
+			"HasTrackList":        {Value: false, Writable: false, Emit: prop.EmitFalse},
+			"Identity":            {Value: "go-musicfox", Writable: false, Emit: prop.EmitFalse},
+			"SupportedUriSchemes": {Value: []string{"http", "https"}, Writable: false, Emit: prop.EmitFalse},
+			"SupportedMimeTypes":  {Value: []string{}, Writable: false, Emit: prop.EmitFalse},
+		},
+		playerIface: {
+			"PlaybackStatus": {Value: playbackStatus(h.p.State()), Writable: false, Emit: prop.EmitTrue},
+			"LoopStatus":     {Value: "None", Writable: true, Emit: prop.EmitTrue, Callback: h.onSetLoopStatus},
+			"Rate":           {Value: 1.0, Writable: false, Emit: prop.EmitFalse},
+			"Shuffle":        {Value: false, Writable: true, Emit: prop.EmitTrue, Callback: h.onSetShuffle},
+			"Metadata":       {Value: h.metadata(), Writable: false, Emit: prop.EmitTrue},
+			"Volume":         {Value: h.volume, Writable: true, Emit: prop.EmitTrue, Callback: h.onSetVolume},
+			"Position":       {Value: int64(0), Writable: false, Emit: prop.EmitFalse},
+			"MinimumRate":    {Value: 1.0, Writable: false, Emit: prop.EmitFalse},
+			"MaximumRate":    {Value: 1.0, Writable: false, Emit: prop.EmitFalse},
+			"CanGoNext":      {Value: true, Writable: false, Emit: prop.EmitTrue},
+			"CanGoPrevious":  {Value: true, Writable: false, Emit: prop.EmitTrue},
+			"CanPlay":        {Value: true, Writable: false, Emit: prop.EmitTrue},
+			"CanPause":       {Value: true, Writable: false, Emit: prop.EmitTrue},
+			"CanSeek":        {Value: true, Writable: false, Emit: prop.EmitTrue},
+			"CanControl":     {Value: true, Writable: false, Emit: prop.EmitFalse},
+		},
+	}
+}
+
+// metadata builds the MPRIS2 "Metadata" dict from the currently playing
+// track.
+func (h *Handler) metadata() map[string]dbus.Variant {
+	music := h.p.CurMusic()
+	return map[string]dbus.Variant{
+		"mpris:trackid": dbus.MakeVariant(dbus.ObjectPath("/org/mpris/MediaPlayer2/musicfox/track")),
+		"mpris:length":  dbus.MakeVariant(music.Duration.Microseconds()),
+		"xesam:title":   dbus.MakeVariant(music.Title),
+		"xesam:album":   dbus.MakeVariant(music.Album),
+		"xesam:artist":  dbus.MakeVariant([]string{music.Artist}),
+		"mpris:artUrl":  dbus.MakeVariant(music.PicUrl),
+	}
+}
+
+func microsToDuration(us int64) time.Duration {
+	return time.Duration(us) * time.Microsecond
+}
+
+// onSetVolume backs Properties.Set("Volume"): the Player interface only
+// exposes relative UpVolume/DownVolume (each a 5-point step), so we repeat
+// whichever one enough times to cover the requested delta, then record the
+// percentage we actually reached rather than the client's requested target
+// -- tracking the target instead would desync h.volume from the real
+// player by more and more on every subsequent nudge.
+func (h *Handler) onSetVolume(c *prop.Change) *dbus.Error {
+	newVolume, ok := c.Value.(float64)
+	if !ok {
+		return nil
+	}
+	const step = 5
+	from := int(math.Round(h.volume * 100))
+	to := int(math.Round(newVolume * 100))
+	if to > 100 {
+		to = 100
+	} else if to < 0 {
+		to = 0
+	}
+
+	steps := (to - from) / step
+	if steps > 0 {
+		for i := 0; i < steps; i++ {
+			h.p.UpVolume()
+		}
+		from += steps * step
+	} else if steps < 0 {
+		for i := 0; i < -steps; i++ {
+			h.p.DownVolume()
+		}
+		from += steps * step
+	}
+	if from > 100 {
+		from = 100
+	} else if from < 0 {
+		from = 0
+	}
+	h.volume = float64(from) / 100
+	return nil
+}
+
+// onSetShuffle backs Properties.Set("Shuffle"). Without a queue, shuffle
+// is purely cosmetic on the bus.
+func (h *Handler) onSetShuffle(c *prop.Change) *dbus.Error {
+	if h.queue == nil {
+		return nil
+	}
+	on, _ := c.Value.(bool)
+	h.queue.SetShuffle(on)
+	return nil
+}
+
+// onSetLoopStatus backs Properties.Set("LoopStatus"), mapping the MPRIS2
+// enum onto player.RepeatMode.
+func (h *Handler) onSetLoopStatus(c *prop.Change) *dbus.Error {
+	if h.queue == nil {
+		return nil
+	}
+	switch status, _ := c.Value.(string); status {
+	case "Track":
+		h.queue.SetRepeat(player.RepeatOne)
+	case "Playlist":
+		h.queue.SetRepeat(player.RepeatAll)
+	default:
+		h.queue.SetRepeat(player.RepeatOff)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+//go:build linux
+
+// Package mpris implements the MPRIS2 D-Bus interfaces (org.mpris.MediaPlayer2
+// and org.mpris.MediaPlayer2.Player) on top of the go-musicfox Player
+// interface, so desktop environments, media keys and tools such as
+// playerctl or KDE Connect can control a running instance.
+package mpris
+
+import (
+	"go-musicfox/pkg/player"
+	"go-musicfox/utils"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+const (
+	busName        = "org.mpris.MediaPlayer2.musicfox"
+	objectPath     = "/org/mpris/MediaPlayer2"
+	rootIface      = "org.mpris.MediaPlayer2"
+	playerIface    = "org.mpris.MediaPlayer2.Player"
+	propsIntrospec = introspect.IntrospectDataString
+)
+
+// PlaylistController is the subset of the playlist controller needed to
+// implement Next/Previous, kept minimal so mpris doesn't depend on the TUI
+// model package.
+type PlaylistController interface {
+	NextSong()
+	PreviousSong()
+}
+
+// Handler wires a player.Player onto the session bus as
+// org.mpris.MediaPlayer2.musicfox.
+type Handler struct {
+	conn  *dbus.Conn
+	p     player.Player
+	ctrl  PlaylistController
+	queue *player.Queue // optional; nil disables Shuffle/LoopStatus control
+	props *prop.Properties
+
+	volume     float64 // Player only exposes UpVolume/DownVolume, not an absolute getter, so track our own estimate
+	curSongURL string  // last track Metadata was published for
+
+	close chan struct{}
+}
+
+// NewHandler connects to the session bus, exports the MPRIS2 interfaces and
+// starts forwarding Player state changes as PropertiesChanged signals.
+// queue may be nil, in which case Shuffle/LoopStatus can still be toggled
+// by a client but won't affect playback.
+//
+// Callers are expected to only invoke this when `config.Mpris.Enable` is
+// true; on unsupported platforms this file is simply excluded from the
+// build.
+func NewHandler(p player.Player, ctrl PlaylistController, queue *player.Queue) (*Handler, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Handler{
+		conn:   conn,
+		p:      p,
+		ctrl:   ctrl,
+		queue:  queue,
+		volume: 0.5,
+		close:  make(chan struct{}),
+	}
+
+	if err = conn.Export(rootAdapter{h}, objectPath, rootIface); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if err = conn.Export(playerAdapter{h}, objectPath, playerIface); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	h.props, err = prop.Export(conn, objectPath, h.propSpec())
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if err = conn.Export(introspect.NewIntrospectable(&introspect.Node{
+		Name: objectPath,
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			prop.IntrospectData,
+		},
+	}), objectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	reply, err := conn.RequestName(busName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		_ = conn.Close()
+		return nil, dbus.ErrNotConnected
+	}
+
+	go func() {
+		defer utils.Recover(false)
+		h.listen()
+	}()
+
+	return h, nil
+}
+
+// listen forwards Player state/time changes onto the bus as
+// PropertiesChanged signals.
+func (h *Handler) listen() {
+	for {
+		select {
+		case <-h.close:
+			return
+		case state := <-h.p.StateChan():
+			// SetMust, not Set: Set is the D-Bus Properties.Set method and
+			// rejects these read-only properties with ErrReadOnly.
+			h.props.SetMust(playerIface, "PlaybackStatus", playbackStatus(state))
+			// Metadata is otherwise only computed once at startup, so a
+			// client would keep showing the first track forever; refresh
+			// it whenever playback moves onto a new URL.
+			if state == player.Playing {
+				if music := h.p.CurMusic(); music.Url != h.curSongURL {
+					h.curSongURL = music.Url
+					h.props.SetMust(playerIface, "Metadata", h.metadata())
+				}
+			}
+		case passed := <-h.p.TimeChan():
+			// Position is stored for clients to query but not signalled (its
+			// Emit is EmitFalse); no Seeked signal is sent on explicit seeks.
+			h.props.SetMust(playerIface, "Position", passed.Microseconds())
+		}
+	}
+}
+
+// Close unregisters from the bus and stops the forwarding goroutine.
+func (h *Handler) Close() {
+	close(h.close)
+	_, _ = h.conn.ReleaseName(busName)
+	_ = h.conn.Close()
+}
+
+func playbackStatus(state player.State) string {
+	switch state {
+	case player.Playing:
+		return "Playing"
+	case player.Paused:
+		return "Paused"
+	default:
+		return "Stopped"
+	}
+}
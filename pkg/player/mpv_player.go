@@ -0,0 +1,400 @@
+package player
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go-musicfox/utils"
+	"net"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mpvCommand is a single JSON-IPC request, as documented at
+// https://mpv.io/manual/master/#json-ipc.
+type mpvCommand struct {
+	Command   []interface{} `json:"command"`
+	RequestID int64         `json:"request_id,omitempty"`
+}
+
+// mpvMessage covers both command replies and observe_property events;
+// which fields are populated depends on which of the two it is.
+type mpvMessage struct {
+	// Reply fields.
+	Error     string      `json:"error"`
+	Data      interface{} `json:"data"`
+	RequestID int64       `json:"request_id"`
+
+	// Event fields (observe_property events reuse Data for the new value).
+	Event string `json:"event"`
+	Name  string `json:"name"`
+}
+
+type mpvPlayer struct {
+	bin        string
+	socketPath string
+	cmd        *exec.Cmd
+
+	l    sync.Mutex
+	conn net.Conn
+
+	// curMusic/state/passed are written from readLoop's onPropertyChange
+	// (its own goroutine), from listen() (another goroutine), and read
+	// from whatever goroutine calls the Player API (CurMusic/State/
+	// PassedTime/Paused/...), so they're atomics rather than plain
+	// fields, mirroring mpdPlayer's *Val pattern.
+	curMusicVal atomic.Value // UrlMusic
+	stateVal    atomic.Value // State
+	passedVal   atomic.Value // time.Duration
+	volume      int32        // atomic; percent, 0-100
+
+	nextReqId int64
+	pending   sync.Map // map[int64]chan mpvMessage
+
+	timeChan  chan time.Duration
+	stateChan chan State
+	musicChan chan UrlMusic
+	errChan   chan error
+
+	close chan struct{}
+}
+
+// NewMpvPlayer spawns `mpv --idle --input-ipc-server=<socketPath>
+// --no-video --really-quiet` and drives it over its JSON-IPC socket. It
+// satisfies the Player interface the same way mpdPlayer does, letting
+// `player.engine: mpv` be selected in config for gapless/HLS/opus
+// playback without requiring an MPD install.
+func NewMpvPlayer(bin, socketPath string) Player {
+	if _, err := exec.LookPath(bin); err != nil {
+		panic(fmt.Sprintf("mpv未找到: %s, 详情:\n%s", bin, err))
+	}
+
+	p := &mpvPlayer{
+		bin:        bin,
+		socketPath: socketPath,
+		timeChan:   make(chan time.Duration),
+		stateChan:  make(chan State),
+		musicChan:  make(chan UrlMusic),
+		errChan:    make(chan error, 8),
+		close:      make(chan struct{}),
+	}
+	p.stateVal.Store(Stopped)
+	p.passedVal.Store(time.Duration(0))
+
+	p.spawn()
+
+	go func() {
+		defer utils.Recover(false)
+		p.listen()
+	}()
+
+	return p
+}
+
+// spawn starts the mpv subprocess and blocks until its IPC socket is
+// reachable, analogous to mpdPlayer's own dial-on-startup.
+func (p *mpvPlayer) spawn() {
+	p.cmd = exec.Command(p.bin,
+		"--idle",
+		"--no-video",
+		"--really-quiet",
+		"--input-ipc-server="+p.socketPath,
+	)
+	if err := p.cmd.Start(); err != nil {
+		panic(fmt.Sprintf("mpv启动失败: %s", err))
+	}
+	registerChild(p.cmd)
+
+	go func() {
+		defer utils.Recover(false)
+		_ = p.cmd.Wait()
+		unregisterChild(p.cmd)
+	}()
+
+	p.client() // block until the socket accepts connections
+	p.observe("time-pos", "pause", "eof-reached")
+}
+
+// client returns the current IPC connection, redialing with backoff if it
+// has dropped (e.g. mpv restarted), analogous to mpdPlayer.client()'s
+// redial. There is no ping: a connection counts as dropped once readLoop
+// has seen it close and cleared p.conn.
+func (p *mpvPlayer) client() net.Conn {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if p.conn != nil {
+		return p.conn
+	}
+
+	backoff := 100 * time.Millisecond
+	for {
+		conn, err := net.Dial("unix", p.socketPath)
+		if err == nil {
+			p.conn = conn
+			go func() {
+				defer utils.Recover(false)
+				p.readLoop(conn)
+			}()
+			return p.conn
+		}
+
+		select {
+		case <-p.close:
+			return nil
+		case <-time.After(backoff):
+		}
+		if backoff < 2*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+// readLoop decodes newline-delimited JSON messages off the socket until
+// it closes, dispatching replies to whoever is waiting on RequestID and
+// turning observe_property events into timeChan/stateChan updates.
+func (p *mpvPlayer) readLoop(conn net.Conn) {
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var msg mpvMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+
+		if msg.Event == "property-change" {
+			p.onPropertyChange(msg)
+			continue
+		}
+
+		if ch, ok := p.pending.LoadAndDelete(msg.RequestID); ok {
+			ch.(chan mpvMessage) <- msg
+		}
+	}
+
+	p.l.Lock()
+	p.conn = nil
+	p.l.Unlock()
+}
+
+func (p *mpvPlayer) onPropertyChange(msg mpvMessage) {
+	switch msg.Name {
+	case "time-pos":
+		if secs, ok := msg.Data.(float64); ok {
+			passed := time.Duration(secs * float64(time.Second))
+			p.passedVal.Store(passed)
+			select {
+			case p.timeChan <- passed:
+			default:
+			}
+		}
+	case "pause":
+		paused, _ := msg.Data.(bool)
+		if paused {
+			p.setState(Paused)
+		} else if p.State() != Stopped {
+			p.setState(Playing)
+		}
+	case "eof-reached":
+		if done, _ := msg.Data.(bool); done {
+			p.setState(Stopped)
+		}
+	}
+}
+
+func (p *mpvPlayer) setState(state State) {
+	p.stateVal.Store(state)
+	select {
+	case p.stateChan <- state:
+	default:
+	}
+}
+
+// command sends a JSON-IPC command and blocks for its reply.
+func (p *mpvPlayer) command(args ...interface{}) (mpvMessage, error) {
+	conn := p.client()
+	if conn == nil {
+		return mpvMessage{}, fmt.Errorf("mpv连接已关闭")
+	}
+
+	id := atomic.AddInt64(&p.nextReqId, 1)
+	reply := make(chan mpvMessage, 1)
+	p.pending.Store(id, reply)
+
+	payload, err := json.Marshal(mpvCommand{Command: args, RequestID: id})
+	if err != nil {
+		return mpvMessage{}, err
+	}
+
+	p.l.Lock()
+	_, err = conn.Write(append(payload, '\n'))
+	p.l.Unlock()
+	if err != nil {
+		p.pending.Delete(id)
+		return mpvMessage{}, err
+	}
+
+	select {
+	case msg := <-reply:
+		if msg.Error != "" && msg.Error != "success" {
+			return msg, fmt.Errorf("mpv: %s", msg.Error)
+		}
+		return msg, nil
+	case <-time.After(5 * time.Second):
+		p.pending.Delete(id)
+		return mpvMessage{}, fmt.Errorf("mpv命令超时")
+	}
+}
+
+func (p *mpvPlayer) observe(properties ...string) {
+	for i, name := range properties {
+		_, err := p.command("observe_property", i+1, name)
+		mpdErrorHandler(err, true)
+	}
+}
+
+// listen mirrors mpdPlayer.doPlay(): it owns curMusic and issues
+// loadfile/volume commands whenever a new track comes in on musicChan.
+func (p *mpvPlayer) listen() {
+	for {
+		select {
+		case <-p.close:
+			return
+		case music := <-p.musicChan:
+			p.curMusicVal.Store(music)
+			_, err := p.command("loadfile", music.Url, "replace")
+			mpdErrorHandler(err, true)
+			p.passedVal.Store(time.Duration(0))
+			p.setState(Playing)
+		}
+	}
+}
+
+// Play hands music to listen() over musicChan, blocking (up to a generous
+// timeout) rather than giving up with a non-blocking send: musicChan is
+// unbuffered and listen() can be mid-command("loadfile", ...) for up to 5s,
+// so a `select { ... default: }` here would silently drop a track change
+// that arrives while the previous one is still loading (e.g. a jukebox
+// skip right after start, or a quick double Next).
+func (p *mpvPlayer) Play(songType SongType, url string, duration time.Duration) {
+	music := UrlMusic{Url: url, Type: songType, Duration: duration}
+	select {
+	case p.musicChan <- music:
+	case <-p.close:
+	case <-time.After(10 * time.Second):
+		utils.Logger().Printf("err: mpv player繁忙, Play指令超时")
+	}
+}
+
+func (p *mpvPlayer) CurMusic() UrlMusic {
+	if v := p.curMusicVal.Load(); v != nil {
+		return v.(UrlMusic)
+	}
+	return UrlMusic{}
+}
+
+func (p *mpvPlayer) Paused() {
+	if p.State() != Playing {
+		return
+	}
+	_, err := p.command("set_property", "pause", true)
+	mpdErrorHandler(err, true)
+	p.setState(Paused)
+}
+
+func (p *mpvPlayer) Resume() {
+	if p.State() == Playing {
+		return
+	}
+	_, err := p.command("set_property", "pause", false)
+	mpdErrorHandler(err, true)
+	p.setState(Playing)
+}
+
+func (p *mpvPlayer) Stop() {
+	if p.State() == Stopped {
+		return
+	}
+	_, err := p.command("stop")
+	mpdErrorHandler(err, true)
+	p.setState(Stopped)
+}
+
+func (p *mpvPlayer) Toggle() {
+	switch p.State() {
+	case Paused, Stopped:
+		p.Resume()
+	case Playing:
+		p.Paused()
+	}
+}
+
+func (p *mpvPlayer) Seek(duration time.Duration) {
+	_, err := p.command("set_property", "time-pos", duration.Seconds())
+	mpdErrorHandler(err, true)
+	p.passedVal.Store(duration)
+}
+
+func (p *mpvPlayer) PassedTime() time.Duration {
+	if v := p.passedVal.Load(); v != nil {
+		return v.(time.Duration)
+	}
+	return 0
+}
+
+func (p *mpvPlayer) TimeChan() <-chan time.Duration {
+	return p.timeChan
+}
+
+func (p *mpvPlayer) State() State {
+	if v := p.stateVal.Load(); v != nil {
+		return v.(State)
+	}
+	return Stopped
+}
+
+func (p *mpvPlayer) StateChan() <-chan State {
+	return p.stateChan
+}
+
+// ErrChan has the same signature as mpdPlayer.ErrChan(), but mpvPlayer
+// sends nothing on it: IPC errors are only logged.
+func (p *mpvPlayer) ErrChan() <-chan error {
+	return p.errChan
+}
+
+func (p *mpvPlayer) UpVolume() {
+	volume := atomic.AddInt32(&p.volume, 5)
+	if volume > 100 {
+		volume = 100
+		atomic.StoreInt32(&p.volume, volume)
+	}
+	_, err := p.command("set_property", "volume", volume)
+	mpdErrorHandler(err, true)
+}
+
+func (p *mpvPlayer) DownVolume() {
+	volume := atomic.AddInt32(&p.volume, -5)
+	if volume < 0 {
+		volume = 0
+		atomic.StoreInt32(&p.volume, volume)
+	}
+	_, err := p.command("set_property", "volume", volume)
+	mpdErrorHandler(err, true)
+}
+
+func (p *mpvPlayer) Close() {
+	close(p.close)
+
+	p.l.Lock()
+	if p.conn != nil {
+		_ = p.conn.Close()
+	}
+	p.l.Unlock()
+
+	if p.cmd != nil && p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+		unregisterChild(p.cmd)
+	}
+}
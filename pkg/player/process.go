@@ -0,0 +1,38 @@
+package player
+
+import (
+	"os/exec"
+	"sync"
+)
+
+// children tracks backend subprocesses passed to registerChild (the mpv
+// backend registers its mpv process) so the top-level Ctrl-C handler can
+// make sure none of them outlive the TUI, instead of relying on each
+// backend's Close() to have been reached. MPD is not tracked here;
+// mpdPlayer's Close() stops it with `--kill`.
+var children sync.Map // map[*exec.Cmd]struct{}
+
+// registerChild records cmd as a subprocess owned by a Player backend.
+func registerChild(cmd *exec.Cmd) {
+	children.Store(cmd, struct{}{})
+}
+
+// unregisterChild forgets cmd once its owning backend has shut it down
+// cleanly.
+func unregisterChild(cmd *exec.Cmd) {
+	children.Delete(cmd)
+}
+
+// KillAllChildren force-kills every subprocess still registered by a
+// Player backend. Call this from the top-level SIGINT handler so mpv
+// children don't outlive the process on Ctrl-C even if a particular
+// backend's Close() didn't run to completion.
+func KillAllChildren() {
+	children.Range(func(key, _ interface{}) bool {
+		if cmd, ok := key.(*exec.Cmd); ok && cmd.Process != nil {
+			_ = cmd.Process.Kill()
+		}
+		children.Delete(key)
+		return true
+	})
+}
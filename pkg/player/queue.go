@@ -0,0 +1,218 @@
+package player
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// RepeatMode controls how Queue advances when Next/Previous walks off
+// either end of the track list.
+type RepeatMode int
+
+const (
+	RepeatOff RepeatMode = iota
+	RepeatAll
+	RepeatOne
+)
+
+// Queue is an ordered, mutable playback queue mirroring Navidrome's
+// jukebox device model: a backend that holds one (mpdPlayer, via Queue())
+// asks the Queue "what do I play now/next" instead of tracking its own
+// index. mpvPlayer does not use a Queue.
+type Queue struct {
+	l sync.Mutex
+
+	tracks  []UrlMusic
+	current int
+	order   []int // playback order when shuffle is on, indexes into tracks
+
+	gain    float64
+	shuffle bool
+	repeat  RepeatMode
+}
+
+// NewQueue returns an empty Queue with unity gain.
+func NewQueue() *Queue {
+	return &Queue{gain: 1}
+}
+
+// Set replaces the whole queue and positions it at index.
+func (q *Queue) Set(tracks []UrlMusic, index int) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	q.tracks = append([]UrlMusic(nil), tracks...)
+	q.current = clampIndex(index, len(q.tracks))
+	q.reshuffle()
+}
+
+// Add appends tracks to the end of the queue.
+func (q *Queue) Add(tracks ...UrlMusic) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	q.tracks = append(q.tracks, tracks...)
+	q.reshuffle()
+}
+
+// Remove drops the track at index, adjusting current if necessary.
+func (q *Queue) Remove(index int) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	if index < 0 || index >= len(q.tracks) {
+		return
+	}
+	q.tracks = append(q.tracks[:index], q.tracks[index+1:]...)
+	if q.current > index {
+		q.current--
+	} else if q.current >= len(q.tracks) {
+		q.current = len(q.tracks) - 1
+	}
+	q.reshuffle()
+}
+
+// Clear empties the queue.
+func (q *Queue) Clear() {
+	q.l.Lock()
+	defer q.l.Unlock()
+	q.tracks = nil
+	q.order = nil
+	q.current = 0
+}
+
+// Current returns the track at the current position, if any.
+func (q *Queue) Current() (UrlMusic, bool) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	return q.at(q.current)
+}
+
+// Skip jumps to the given position within the queue (not the shuffled
+// order) and returns the track there.
+func (q *Queue) Skip(index int) (UrlMusic, bool) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	if index < 0 || index >= len(q.tracks) {
+		return UrlMusic{}, false
+	}
+	q.current = index
+	return q.tracks[index], true
+}
+
+// Next advances the queue according to the current RepeatMode and returns
+// the new current track.
+func (q *Queue) Next() (UrlMusic, bool) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	if len(q.tracks) == 0 {
+		return UrlMusic{}, false
+	}
+
+	if q.repeat == RepeatOne {
+		return q.at(q.current)
+	}
+
+	pos := q.currentOrderPos()
+	pos++
+	if pos >= len(q.order) {
+		if q.repeat != RepeatAll {
+			return UrlMusic{}, false
+		}
+		pos = 0
+	}
+	q.current = q.order[pos]
+	return q.at(q.current)
+}
+
+// Previous is the mirror of Next.
+func (q *Queue) Previous() (UrlMusic, bool) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	if len(q.tracks) == 0 {
+		return UrlMusic{}, false
+	}
+
+	pos := q.currentOrderPos()
+	pos--
+	if pos < 0 {
+		if q.repeat != RepeatAll {
+			return UrlMusic{}, false
+		}
+		pos = len(q.order) - 1
+	}
+	q.current = q.order[pos]
+	return q.at(q.current)
+}
+
+// SetShuffle toggles shuffle mode, rebuilding the playback order.
+func (q *Queue) SetShuffle(on bool) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	q.shuffle = on
+	q.reshuffle()
+}
+
+// SetRepeat sets the repeat mode.
+func (q *Queue) SetRepeat(mode RepeatMode) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	q.repeat = mode
+}
+
+// SetGain sets the jukebox output gain (0.0-1.0, as in the Subsonic API).
+func (q *Queue) SetGain(gain float64) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	q.gain = gain
+}
+
+// Snapshot returns the queue contents, current index, shuffle/repeat state
+// and gain, for status reporting (e.g. the jukeboxControl "get"/"status"
+// verbs).
+func (q *Queue) Snapshot() (tracks []UrlMusic, current int, shuffle bool, repeat RepeatMode, gain float64) {
+	q.l.Lock()
+	defer q.l.Unlock()
+	return append([]UrlMusic(nil), q.tracks...), q.current, q.shuffle, q.repeat, q.gain
+}
+
+func (q *Queue) at(index int) (UrlMusic, bool) {
+	if index < 0 || index >= len(q.tracks) {
+		return UrlMusic{}, false
+	}
+	return q.tracks[index], true
+}
+
+func (q *Queue) currentOrderPos() int {
+	for i, idx := range q.order {
+		if idx == q.current {
+			return i
+		}
+	}
+	return 0
+}
+
+// reshuffle rebuilds q.order after the track list or shuffle flag changes,
+// keeping q.current's position fixed when shuffle is off.
+func (q *Queue) reshuffle() {
+	q.order = make([]int, len(q.tracks))
+	for i := range q.order {
+		q.order[i] = i
+	}
+	if !q.shuffle {
+		return
+	}
+	rand.Shuffle(len(q.order), func(i, j int) {
+		q.order[i], q.order[j] = q.order[j], q.order[i]
+	})
+}
+
+func clampIndex(index, length int) int {
+	if length == 0 {
+		return 0
+	}
+	if index < 0 {
+		return 0
+	}
+	if index >= length {
+		return length - 1
+	}
+	return index
+}